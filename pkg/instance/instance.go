@@ -0,0 +1,73 @@
+package instance
+
+import (
+	core "k8s.io/api/core/v1"
+)
+
+// Info contains the information required to configure a Windows instance.
+type Info struct {
+	// Addresses contains every address configured for the instance, in the order they were specified in the
+	// Windows instance ConfigMap. The first entry is treated as the primary address used to reach the
+	// instance over SSH. An instance may have more than one entry when it is dual-stack.
+	Addresses []string
+	// Username is the name of the user that should be used when configuring the instance via SSH.
+	Username string
+	// DNSName is the DNS name associated with the instance, if any.
+	DNSName string
+	// New indicates that no Machine/BYOH object is associated with the instance, and it should be configured
+	// as a new instance.
+	New bool
+	// Node is the existing Node object associated with this instance, if one exists.
+	Node *core.Node
+
+	// The fields below are only populated for instances described via the structured instances.yaml/instances.json
+	// ConfigMap schema, and are left at their zero value for instances described via the legacy flat format.
+
+	// SSHPort is the port to use when connecting to the instance over SSH. Defaults to 22 when unset.
+	SSHPort int32
+	// AuthMethod specifies how to authenticate the SSH connection to the instance.
+	AuthMethod AuthMethod
+	// SecretRef is the name of the Secret, in the operator's namespace, containing the credential identified by
+	// AuthMethod.
+	SecretRef string
+	// Labels are additional labels that should be applied to the instance's Node once configured.
+	Labels map[string]string
+	// Taints are additional taints that should be applied to the instance's Node once configured.
+	Taints []core.Taint
+	// Platform identifies the infrastructure platform the instance runs on, e.g. "vsphere" or "BYOH".
+	Platform string
+	// Hostname is the expected hostname of the instance, used to populate the Node's hostname when it cannot be
+	// determined from the address alone.
+	Hostname string
+}
+
+// AuthMethod identifies how to authenticate an SSH connection to an instance.
+type AuthMethod string
+
+const (
+	// AuthMethodPassword authenticates using a password stored in the instance's SecretRef.
+	AuthMethodPassword AuthMethod = "password"
+	// AuthMethodKey authenticates using a private key stored in the instance's SecretRef.
+	AuthMethodKey AuthMethod = "key"
+	// AuthMethodCertificate authenticates using a certificate stored in the instance's SecretRef.
+	AuthMethodCertificate AuthMethod = "certificate"
+)
+
+// NewInfo returns a new Info object describing an instance with the given addresses.
+func NewInfo(addresses []string, username, dnsName string, new bool, node *core.Node) *Info {
+	return &Info{
+		Addresses: addresses,
+		Username:  username,
+		DNSName:   dnsName,
+		New:       new,
+		Node:      node,
+	}
+}
+
+// Address returns the primary address associated with the instance, or the empty string if none are configured.
+func (i *Info) Address() string {
+	if len(i.Addresses) == 0 {
+		return ""
+	}
+	return i.Addresses[0]
+}
@@ -0,0 +1,109 @@
+package nodeutil
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// ovnPrimaryIfAddrAnnotation holds the primary interface address assigned to the node by OVN-Kubernetes, as a
+	// JSON object with "ipv4" and/or "ipv6" keys, e.g. {"ipv4":"10.0.0.5/24","ipv6":"fd00::5/64"}.
+	ovnPrimaryIfAddrAnnotation = "k8s.ovn.org/node-primary-ifaddr"
+	// ovnHostAddressesAnnotation holds every address assigned to the node's interfaces, as a JSON array of
+	// addresses, e.g. ["10.0.0.5","fd00::5"].
+	ovnHostAddressesAnnotation = "k8s.ovn.org/host-addresses"
+)
+
+// FindByAddress returns the Node within the given list whose addresses include any of the given addresses. In
+// addition to Node.Status.Addresses, the well-known node annotations populated by OVN-Kubernetes are consulted, as
+// kubelet may only report a subset of the addresses actually assigned to the node.
+func FindByAddress(addresses []string, nodes *core.NodeList) (*core.Node, error) {
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if Matches(node, addresses) {
+			return node, nil
+		}
+	}
+	return nil, errors.Errorf("unable to find node with address in %v", addresses)
+}
+
+// Matches returns true if node is associated with any of the given addresses, checking both Node.Status.Addresses
+// and the well-known node annotations populated by OVN-Kubernetes. Callers that already have a candidate Node, such
+// as a username lookup keyed by a previously resolved address, should use this instead of comparing against
+// Node.Status.Addresses alone, so that a Node reachable only through an OVN annotation is still matched.
+func Matches(node *core.Node, addresses []string) bool {
+	for _, nodeAddress := range node.Status.Addresses {
+		if matchesAny(nodeAddress.Address, addresses) {
+			return true
+		}
+	}
+	for _, nodeAddress := range ovnAnnotationAddresses(node) {
+		if matchesAny(nodeAddress, addresses) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByName returns the Node within the given list with the given name, or an error if no such Node exists. This
+// is used to look up the Node associated with a ConfigMap entry whose key is not itself an address, such as an
+// entry using the annotation address-resolver method, where the key is expected to be the instance's Node name.
+func FindByName(name string, nodes *core.NodeList) (*core.Node, error) {
+	for i := range nodes.Items {
+		if nodes.Items[i].GetName() == name {
+			return &nodes.Items[i], nil
+		}
+	}
+	return nil, errors.Errorf("unable to find node with name %s", name)
+}
+
+// matchesAny returns true if address is equal to any entry in addresses
+func matchesAny(address string, addresses []string) bool {
+	for _, candidate := range addresses {
+		if candidate == address {
+			return true
+		}
+	}
+	return false
+}
+
+// ovnAnnotationAddresses returns the addresses assigned to the node according to the OVN-Kubernetes node
+// annotations: the addresses from the primary-ifaddr annotation followed by every entry of the host-addresses
+// annotation, whichever of the two are present. A missing or unparsable annotation is not treated as an error, since
+// not every cluster runs OVN-Kubernetes.
+func ovnAnnotationAddresses(node *core.Node) []string {
+	var addresses []string
+	if raw, ok := node.Annotations[ovnPrimaryIfAddrAnnotation]; ok {
+		var primary struct {
+			IPv4 string `json:"ipv4"`
+			IPv6 string `json:"ipv6"`
+		}
+		if err := json.Unmarshal([]byte(raw), &primary); err == nil {
+			for _, cidr := range []string{primary.IPv4, primary.IPv6} {
+				if address := addressFromCIDR(cidr); address != "" {
+					addresses = append(addresses, address)
+				}
+			}
+		}
+	}
+	if raw, ok := node.Annotations[ovnHostAddressesAnnotation]; ok {
+		var hostAddresses []string
+		if err := json.Unmarshal([]byte(raw), &hostAddresses); err == nil {
+			addresses = append(addresses, hostAddresses...)
+		}
+	}
+	return addresses
+}
+
+// addressFromCIDR strips the prefix length from a CIDR string, e.g. "10.0.0.5/24" becomes "10.0.0.5". If cidr does
+// not contain a "/", it is returned unchanged.
+func addressFromCIDR(cidr string) string {
+	for i := 0; i < len(cidr); i++ {
+		if cidr[i] == '/' {
+			return cidr[:i]
+		}
+	}
+	return cidr
+}
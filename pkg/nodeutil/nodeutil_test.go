@@ -0,0 +1,83 @@
+package nodeutil
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func nodeWithStatusAddress(name, address string) core.Node {
+	return core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: core.NodeStatus{
+			Addresses: []core.NodeAddress{{Type: core.NodeInternalIP, Address: address}},
+		},
+	}
+}
+
+func TestFindByAddressStatusAddress(t *testing.T) {
+	nodes := &core.NodeList{Items: []core.Node{nodeWithStatusAddress("node-a", "10.0.0.5")}}
+	node, err := FindByAddress([]string{"10.0.0.5"}, nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node.GetName() != "node-a" {
+		t.Errorf("got node %q, want %q", node.GetName(), "node-a")
+	}
+}
+
+func TestFindByAddressPrimaryIfAddrAnnotation(t *testing.T) {
+	node := nodeWithStatusAddress("node-a", "10.0.0.5")
+	node.Annotations = map[string]string{
+		ovnPrimaryIfAddrAnnotation: `{"ipv4":"192.168.1.5/24","ipv6":"fd00::5/64"}`,
+	}
+	nodes := &core.NodeList{Items: []core.Node{node}}
+
+	if _, err := FindByAddress([]string{"192.168.1.5"}, nodes); err != nil {
+		t.Errorf("expected primary-ifaddr IPv4 to match: %v", err)
+	}
+	if _, err := FindByAddress([]string{"fd00::5"}, nodes); err != nil {
+		t.Errorf("expected primary-ifaddr IPv6 to match: %v", err)
+	}
+}
+
+func TestFindByAddressHostAddressesAnnotation(t *testing.T) {
+	node := nodeWithStatusAddress("node-a", "10.0.0.5")
+	node.Annotations = map[string]string{
+		ovnHostAddressesAnnotation: `["172.16.0.5","fd00::9"]`,
+	}
+	nodes := &core.NodeList{Items: []core.Node{node}}
+
+	if _, err := FindByAddress([]string{"fd00::9"}, nodes); err != nil {
+		t.Errorf("expected host-addresses entry to match: %v", err)
+	}
+}
+
+func TestFindByAddressNotFound(t *testing.T) {
+	nodes := &core.NodeList{Items: []core.Node{nodeWithStatusAddress("node-a", "10.0.0.5")}}
+	if _, err := FindByAddress([]string{"10.0.0.9"}, nodes); err == nil {
+		t.Error("expected an error when no node matches")
+	}
+}
+
+func TestFindByName(t *testing.T) {
+	nodes := &core.NodeList{Items: []core.Node{nodeWithStatusAddress("node-a", "10.0.0.5")}}
+	if _, err := FindByName("node-a", nodes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := FindByName("node-b", nodes); err == nil {
+		t.Error("expected an error when no node matches")
+	}
+}
+
+func TestMatchesMalformedAnnotationIsIgnored(t *testing.T) {
+	node := nodeWithStatusAddress("node-a", "10.0.0.5")
+	node.Annotations = map[string]string{ovnPrimaryIfAddrAnnotation: `not-json`}
+	if Matches(&node, []string{"not-json"}) {
+		t.Error("a malformed annotation should not produce a match, not be treated as a literal address")
+	}
+	if !Matches(&node, []string{"10.0.0.5"}) {
+		t.Error("expected the Status.Addresses entry to still match despite the malformed annotation")
+	}
+}
@@ -0,0 +1,29 @@
+package resolver
+
+import (
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+)
+
+// annotationResolver reads the instance's address from a Node annotation.
+type annotationResolver struct {
+	annotationKey string
+}
+
+func newAnnotationResolver(_, param string) (Resolver, error) {
+	if param == "" {
+		return nil, errors.New("annotation address resolver requires an annotation key parameter, e.g. address-resolver=annotation:my.domain/address")
+	}
+	return &annotationResolver{annotationKey: param}, nil
+}
+
+func (r *annotationResolver) Resolve(node *core.Node) (string, error) {
+	if node == nil {
+		return "", errors.Errorf("cannot resolve annotation %s without an associated node", r.annotationKey)
+	}
+	address, ok := node.Annotations[r.annotationKey]
+	if !ok || address == "" {
+		return "", errors.Errorf("node %s has no annotation %s", node.GetName(), r.annotationKey)
+	}
+	return address, nil
+}
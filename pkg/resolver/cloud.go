@@ -0,0 +1,56 @@
+package resolver
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+)
+
+// cloudProviderResolver looks up the current address of a cloud instance, identified by instanceID, through the
+// given cloud provider.
+type cloudProviderResolver interface {
+	ResolveInstanceAddress(instanceID string) (string, error)
+}
+
+// cloudProviders maps a provider name, as given in an "address-resolver=cloud:<provider>" field, to the resolver
+// that queries it. Providers register themselves here, and with the Cloud method, via RegisterCloudProvider, e.g.
+// from the init() function of a package implementing oVirt/vSphere guest agent lookups or the AWS instance
+// metadata service.
+var cloudProviders = map[string]cloudProviderResolver{}
+
+// registerCloudMethodOnce ensures the Cloud method is registered with the resolver registry exactly once, the
+// first time a provider is registered. Until then, address-resolver=cloud:<provider> fails fast with "unknown
+// address resolver method" instead of being accepted and always failing at Resolve.
+var registerCloudMethodOnce sync.Once
+
+// RegisterCloudProvider registers a cloud provider under the given name, making
+// "address-resolver=cloud:<name>" resolve instance addresses through it. It is intended to be called from the
+// init() function of a package implementing a specific provider.
+func RegisterCloudProvider(name string, p cloudProviderResolver) {
+	cloudProviders[name] = p
+	registerCloudMethodOnce.Do(func() {
+		Register(Cloud, newCloudResolver)
+	})
+}
+
+// cloudResolver queries a cloud provider for the current address of the instance identified by instanceID.
+type cloudResolver struct {
+	provider   string
+	instanceID string
+}
+
+func newCloudResolver(key, param string) (Resolver, error) {
+	if param == "" {
+		return nil, errors.New("cloud address resolver requires a provider parameter, e.g. address-resolver=cloud:vsphere")
+	}
+	return &cloudResolver{provider: param, instanceID: key}, nil
+}
+
+func (r *cloudResolver) Resolve(_ *core.Node) (string, error) {
+	provider, ok := cloudProviders[r.provider]
+	if !ok {
+		return "", errors.Errorf("unsupported cloud address resolver provider %q", r.provider)
+	}
+	return provider.ResolveInstanceAddress(r.instanceID)
+}
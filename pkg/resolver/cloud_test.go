@@ -0,0 +1,47 @@
+package resolver
+
+import "testing"
+
+type fakeCloudProvider struct {
+	address string
+	err     error
+}
+
+func (p *fakeCloudProvider) ResolveInstanceAddress(_ string) (string, error) {
+	return p.address, p.err
+}
+
+// TestCloudProviderRegistration checks both halves of the deferred-registration contract in cloud.go: that Cloud
+// is not a usable address resolver method until a provider registers itself, and that it becomes usable for every
+// provider name, not just the one that triggered the registration, once one does.
+func TestCloudProviderRegistration(t *testing.T) {
+	if _, ok := registry[Cloud]; ok {
+		t.Fatal("cloud method should not be registered until a provider registers itself")
+	}
+	if _, err := New(Cloud, "instance-1", "vsphere"); err == nil {
+		t.Fatal("expected an error resolving an unregistered method")
+	}
+
+	RegisterCloudProvider("test-provider", &fakeCloudProvider{address: "10.0.0.9"})
+	defer delete(cloudProviders, "test-provider")
+
+	r, err := New(Cloud, "instance-1", "test-provider")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	address, err := r.Resolve(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if address != "10.0.0.9" {
+		t.Errorf("got %q, want %q", address, "10.0.0.9")
+	}
+
+	r, err = New(Cloud, "instance-1", "unregistered-provider")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Resolve(nil); err == nil {
+		t.Error("expected an error for an unregistered provider")
+	}
+}
@@ -0,0 +1,37 @@
+package resolver
+
+import (
+	"net"
+	"sort"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+)
+
+// dnsResolver resolves a hostname to an address every time Resolve is called, so that callers who re-invoke it
+// periodically will notice when the hostname starts resolving to a different address.
+type dnsResolver struct {
+	hostname string
+}
+
+func newDNSResolver(_, param string) (Resolver, error) {
+	if param == "" {
+		return nil, errors.New("dns address resolver requires a hostname parameter, e.g. address-resolver=dns:my-host.example.com")
+	}
+	return &dnsResolver{hostname: param}, nil
+}
+
+func (r *dnsResolver) Resolve(_ *core.Node) (string, error) {
+	addresses, err := net.LookupHost(r.hostname)
+	if err != nil {
+		return "", errors.Wrapf(err, "error looking up DNS for %s", r.hostname)
+	}
+	if len(addresses) == 0 {
+		return "", errors.Errorf("DNS did not resolve %s to an address", r.hostname)
+	}
+	// net.LookupHost does not guarantee a stable order across calls, even when the underlying resolution set is
+	// unchanged. Sort before picking one so that repeated lookups of an unchanged address set return the same
+	// result, rather than causing callers to see spurious address churn.
+	sort.Strings(addresses)
+	return addresses[0], nil
+}
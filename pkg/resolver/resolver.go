@@ -0,0 +1,64 @@
+// Package resolver provides pluggable methods for determining the current address of a Windows instance described
+// in the windows-instances ConfigMap. A ConfigMap entry can opt into a non-static method via the
+// "address-resolver=<method>[:<param>]" field, so that instances whose address changes, or is only discoverable
+// through the platform, can still be described declaratively.
+package resolver
+
+import (
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+)
+
+// Method identifies a registered address resolution method.
+type Method string
+
+const (
+	// Static resolves to the ConfigMap key itself, unchanged. This is the default method.
+	Static Method = "static"
+	// DNS resolves a hostname to an address at parse time, and should be re-resolved periodically by the caller.
+	DNS Method = "dns"
+	// Cloud queries the cloud provider for the current address of an instance, identified by instance ID.
+	Cloud Method = "cloud"
+	// Annotation reads the address from a matching Node annotation.
+	Annotation Method = "annotation"
+)
+
+// Resolver returns the current address of a single instance.
+type Resolver interface {
+	// Resolve returns the current address of the instance. node is the Node associated with the instance, if one
+	// is already known, and may be nil.
+	Resolve(node *core.Node) (string, error)
+}
+
+// Factory creates a Resolver for an instance described by the ConfigMap key, given the parameter that followed the
+// method name in the address-resolver field, if any.
+type Factory func(key, param string) (Resolver, error)
+
+// registry holds the Factory for every registered Method.
+var registry = map[Method]Factory{}
+
+// Register adds a Factory for method to the registry, so that it can be used in the address-resolver field of a
+// windows-instances ConfigMap entry. Intended to be called from the init() function of a method's implementation.
+func Register(method Method, factory Factory) {
+	registry[method] = factory
+}
+
+// New creates a Resolver for the given method, key, and param. An error is returned if method is not registered.
+func New(method Method, key, param string) (Resolver, error) {
+	factory, ok := registry[method]
+	if !ok {
+		return nil, errors.Errorf("unknown address resolver method %q", method)
+	}
+	return factory(key, param)
+}
+
+func init() {
+	Register(Static, newStaticResolver)
+	Register(DNS, newDNSResolver)
+	Register(Annotation, newAnnotationResolver)
+	// Cloud is deliberately not registered here: with no provider registered via RegisterCloudProvider, it would
+	// always fail at Resolve with the same "unsupported provider" error regardless of which provider was asked
+	// for. Leaving it unregistered until a provider exists means address-resolver=cloud:<provider> instead fails
+	// immediately and unambiguously with "unknown address resolver method", for every provider, until a provider
+	// package is added and registers itself.
+}
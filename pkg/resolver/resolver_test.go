@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+)
+
+func TestNewUnknownMethod(t *testing.T) {
+	if _, err := New(Method("bogus"), "key", ""); err == nil {
+		t.Fatal("expected an error for an unregistered method")
+	}
+}
+
+func TestNewStatic(t *testing.T) {
+	r, err := New(Static, "10.0.0.5", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	address, err := r.Resolve(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if address != "10.0.0.5" {
+		t.Errorf("got %q, want %q", address, "10.0.0.5")
+	}
+}
+
+func TestAnnotationResolver(t *testing.T) {
+	r, err := New(Annotation, "some-node", "my.domain/address")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Resolve(nil); err == nil {
+		t.Error("expected an error when no node is given")
+	}
+
+	node := &core.Node{}
+	if _, err := r.Resolve(node); err == nil {
+		t.Error("expected an error when the node has no matching annotation")
+	}
+
+	node.Annotations = map[string]string{"my.domain/address": "10.0.0.5"}
+	address, err := r.Resolve(node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if address != "10.0.0.5" {
+		t.Errorf("got %q, want %q", address, "10.0.0.5")
+	}
+}
+
+func TestAnnotationResolverRequiresParam(t *testing.T) {
+	if _, err := New(Annotation, "some-node", ""); err == nil {
+		t.Fatal("expected an error when no annotation key parameter is given")
+	}
+}
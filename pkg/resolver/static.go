@@ -0,0 +1,17 @@
+package resolver
+
+import core "k8s.io/api/core/v1"
+
+// staticResolver resolves to the ConfigMap key it was created from, unchanged. This reproduces the original
+// behavior of the windows-instances ConfigMap, from before other resolver methods were supported.
+type staticResolver struct {
+	address string
+}
+
+func newStaticResolver(key, _ string) (Resolver, error) {
+	return &staticResolver{address: key}, nil
+}
+
+func (r *staticResolver) Resolve(_ *core.Node) (string, error) {
+	return r.address, nil
+}
@@ -0,0 +1,131 @@
+package wiparser
+
+import (
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/instance"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeutil"
+)
+
+const (
+	// instancesYAMLKey is the windows-instances ConfigMap key holding the structured instance list, in YAML form.
+	instancesYAMLKey = "instances.yaml"
+	// instancesJSONKey is the windows-instances ConfigMap key holding the structured instance list, in JSON form.
+	// JSON is a subset of YAML, so both keys are decoded the same way.
+	instancesJSONKey = "instances.json"
+)
+
+// instanceSpec describes a single instance in the structured instances.yaml/instances.json schema.
+type instanceSpec struct {
+	// Address is the address, or comma-separated list of addresses, used to reach the instance over SSH, and to
+	// match it against a Node.
+	Address string `json:"address"`
+	// Username is the name of the user that should be used when configuring the instance via SSH.
+	Username string `json:"username"`
+	// SSHPort is the port to use when connecting to the instance over SSH. Defaults to 22 when unset.
+	SSHPort int32 `json:"sshPort,omitempty"`
+	// AuthMethod specifies how to authenticate the SSH connection to the instance. Defaults to
+	// instance.AuthMethodPassword when unset.
+	AuthMethod instance.AuthMethod `json:"authMethod,omitempty"`
+	// SecretRef is the name of the Secret, in the operator's namespace, containing the credential identified by
+	// AuthMethod.
+	SecretRef string `json:"secretRef,omitempty"`
+	// Labels are additional labels that should be applied to the instance's Node once configured.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Taints are additional taints that should be applied to the instance's Node once configured.
+	Taints []core.Taint `json:"taints,omitempty"`
+	// Platform identifies the infrastructure platform the instance runs on, e.g. "vsphere" or "BYOH".
+	Platform string `json:"platform,omitempty"`
+	// Hostname is the expected hostname of the instance.
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// instancesSchema is the top-level structure of the instances.yaml/instances.json ConfigMap entry.
+type instancesSchema struct {
+	Instances []instanceSpec `json:"instances"`
+}
+
+// parseStructuredInstances decodes the structured instances.yaml/instances.json entry, if present in
+// instancesData, into a list of instance.Info. It returns an empty, non-nil slice if neither key is present.
+func parseStructuredInstances(instancesData map[string]string, nodes *core.NodeList) ([]*instance.Info, error) {
+	instances := make([]*instance.Info, 0)
+	raw, ok := instancesData[instancesYAMLKey]
+	if !ok {
+		raw, ok = instancesData[instancesJSONKey]
+	}
+	if !ok {
+		return instances, nil
+	}
+
+	var schema instancesSchema
+	if err := yaml.UnmarshalStrict([]byte(raw), &schema); err != nil {
+		return nil, errors.Wrap(err, "unable to decode structured instance list")
+	}
+
+	for _, spec := range schema.Instances {
+		if spec.Address == "" {
+			return nil, errors.New("structured instance entry is missing the required address field")
+		}
+		if spec.Username == "" {
+			return nil, errors.Errorf("structured instance entry for %s is missing the required username field",
+				spec.Address)
+		}
+		addresses := splitAddresses(spec.Address)
+		for _, address := range addresses {
+			if err := validateAddress(address); err != nil {
+				return nil, errors.Wrapf(err, "invalid address %s", address)
+			}
+		}
+		authMethod := spec.AuthMethod
+		if authMethod == "" {
+			authMethod = instance.AuthMethodPassword
+		}
+		sshPort := spec.SSHPort
+		if sshPort == 0 {
+			sshPort = 22
+		}
+
+		node, _ := nodeutil.FindByAddress(addresses, nodes)
+		instances = append(instances, &instance.Info{
+			Addresses:  addresses,
+			Username:   spec.Username,
+			New:        false,
+			Node:       node,
+			SSHPort:    sshPort,
+			AuthMethod: authMethod,
+			SecretRef:  spec.SecretRef,
+			Labels:     spec.Labels,
+			Taints:     spec.Taints,
+			Platform:   spec.Platform,
+			Hostname:   spec.Hostname,
+		})
+	}
+	return instances, nil
+}
+
+// getStructuredNodeUsername returns the username configured for node in the structured instances.yaml/instances.json
+// entry, if present in instancesData and if node matches one of its instance specs. found is false if neither key is
+// present, or if none of the structured entries are associated with node.
+func getStructuredNodeUsername(instancesData map[string]string, node *core.Node) (username string, found bool, err error) {
+	raw, ok := instancesData[instancesYAMLKey]
+	if !ok {
+		raw, ok = instancesData[instancesJSONKey]
+	}
+	if !ok {
+		return "", false, nil
+	}
+
+	var schema instancesSchema
+	if err := yaml.UnmarshalStrict([]byte(raw), &schema); err != nil {
+		return "", false, errors.Wrap(err, "unable to decode structured instance list")
+	}
+
+	for _, spec := range schema.Instances {
+		if nodeutil.Matches(node, splitAddresses(spec.Address)) {
+			return spec.Username, true, nil
+		}
+	}
+	return "", false, nil
+}
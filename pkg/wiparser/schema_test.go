@@ -0,0 +1,129 @@
+package wiparser
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/instance"
+)
+
+func TestParseStructuredInstancesAbsent(t *testing.T) {
+	instances, err := parseStructuredInstances(map[string]string{}, &core.NodeList{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 0 {
+		t.Errorf("got %d instances, want 0", len(instances))
+	}
+}
+
+func TestParseStructuredInstancesYAML(t *testing.T) {
+	instancesData := map[string]string{
+		instancesYAMLKey: `
+instances:
+- address: 10.0.0.5,fd00::5
+  username: Administrator
+- address: 10.0.0.6
+  username: core
+  authMethod: key
+  secretRef: my-secret
+  platform: vsphere
+  hostname: my-host
+`,
+	}
+	instances, err := parseStructuredInstances(instancesData, &core.NodeList{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("got %d instances, want 2", len(instances))
+	}
+	if instances[0].Username != "Administrator" || len(instances[0].Addresses) != 2 {
+		t.Errorf("unexpected first instance: %+v", instances[0])
+	}
+	if instances[1].AuthMethod != instance.AuthMethodKey || instances[1].SecretRef != "my-secret" ||
+		instances[1].Platform != "vsphere" || instances[1].Hostname != "my-host" {
+		t.Errorf("unexpected second instance: %+v", instances[1])
+	}
+	if instances[0].AuthMethod != instance.AuthMethodPassword {
+		t.Errorf("got auth method %q, want default of %q", instances[0].AuthMethod, instance.AuthMethodPassword)
+	}
+}
+
+func TestParseStructuredInstancesMissingRequiredFields(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{name: "missing address", yaml: "instances:\n- username: Administrator\n"},
+		{name: "missing username", yaml: "instances:\n- address: 10.0.0.5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instancesData := map[string]string{instancesYAMLKey: tt.yaml}
+			if _, err := parseStructuredInstances(instancesData, &core.NodeList{}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseStructuredInstancesStrictDecode(t *testing.T) {
+	instancesData := map[string]string{
+		instancesYAMLKey: "instances:\n- address: 10.0.0.5\n  username: Administrator\n  bogusField: true\n",
+	}
+	if _, err := parseStructuredInstances(instancesData, &core.NodeList{}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestParseStructuredInstancesJSON(t *testing.T) {
+	instancesData := map[string]string{
+		instancesJSONKey: `{"instances":[{"address":"10.0.0.5","username":"Administrator"}]}`,
+	}
+	instances, err := parseStructuredInstances(instancesData, &core.NodeList{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 1 || instances[0].Username != "Administrator" {
+		t.Errorf("unexpected instances: %+v", instances)
+	}
+}
+
+func TestGetStructuredNodeUsername(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status:     core.NodeStatus{Addresses: []core.NodeAddress{{Type: core.NodeInternalIP, Address: "10.0.0.5"}}},
+	}
+	instancesData := map[string]string{
+		instancesYAMLKey: "instances:\n- address: 10.0.0.5\n  username: Administrator\n",
+	}
+	username, found, err := getStructuredNodeUsername(instancesData, node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !found || username != "Administrator" {
+		t.Errorf("got (%q, %v), want (Administrator, true)", username, found)
+	}
+
+	other := &core.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}}
+	_, found, err = getStructuredNodeUsername(instancesData, other)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found {
+		t.Error("expected found to be false for an unassociated node")
+	}
+}
+
+func TestGetStructuredNodeUsernameAbsent(t *testing.T) {
+	_, found, err := getStructuredNodeUsername(map[string]string{}, &core.Node{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found {
+		t.Error("expected found to be false when neither structured key is present")
+	}
+}
@@ -0,0 +1,305 @@
+package wiparser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/instance"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeutil"
+)
+
+// resyncPeriod is how often a full reparse is forced, even without a ConfigMap change, so that a dns or cloud
+// address-resolver entry whose underlying address changes is eventually picked up.
+const resyncPeriod = 5 * time.Minute
+
+// EventType identifies the kind of change an InstanceStore observed for an instance.
+type EventType string
+
+const (
+	// Added is emitted the first time an instance is seen.
+	Added EventType = "Added"
+	// Updated is emitted when a previously seen instance's Info changes.
+	Updated EventType = "Updated"
+	// Deleted is emitted when a previously seen instance is no longer described by the ConfigMap.
+	Deleted EventType = "Deleted"
+)
+
+// Event describes a change to a single instance observed by an InstanceStore.
+type Event struct {
+	Type     EventType
+	Instance *instance.Info
+}
+
+// InstanceStore watches the windows-instances ConfigMap and Windows Node objects and maintains an up to date view
+// of the instances they describe, instead of requiring every caller to fetch and parse them on every reconcile.
+// Only a ConfigMap change, or the periodic resync, re-runs address resolution, which may involve a DNS or cloud
+// provider lookup per entry; a Node change cheaply re-associates the already-resolved instances against the latest
+// Node list. All of these happen asynchronously so that a slow or unresponsive DNS server does not block event
+// delivery.
+type InstanceStore struct {
+	client    client.Client
+	namespace string
+
+	mu         sync.RWMutex
+	instances  map[string]*instance.Info // keyed by primary address
+	generation uint64                    // incremented on every reparseFull, to discard superseded results
+
+	events chan Event
+	done   <-chan struct{} // closed when the context passed to NewInstanceStore is done
+}
+
+// NewInstanceStore creates an InstanceStore and starts watching the windows-instances ConfigMap and Windows Node
+// objects through c. cl, typically a cache-backed client.Client, is used to re-fetch and re-parse the ConfigMap and
+// Node list whenever a change is observed. The returned store's Events channel begins receiving events once the
+// underlying informers have synced; the caller is responsible for starting c itself, typically via
+// manager.Manager.
+func NewInstanceStore(ctx context.Context, c cache.Cache, cl client.Client, namespace string) (*InstanceStore, error) {
+	s := &InstanceStore{
+		client:    cl,
+		namespace: namespace,
+		instances: make(map[string]*instance.Info),
+		events:    make(chan Event, 10),
+		done:      ctx.Done(),
+	}
+
+	configMapInformer, err := c.GetInformer(ctx, &core.ConfigMap{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get informer for ConfigMaps")
+	}
+	if _, err := configMapInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { s.onConfigMapChange(obj) },
+		UpdateFunc: func(_, obj interface{}) { s.onConfigMapChange(obj) },
+		DeleteFunc: func(obj interface{}) { s.onConfigMapChange(obj) },
+	}); err != nil {
+		return nil, errors.Wrap(err, "unable to watch ConfigMaps")
+	}
+
+	nodeInformer, err := c.GetInformer(ctx, &core.Node{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get informer for Nodes")
+	}
+	if _, err := nodeInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { s.onNodeChange(obj) },
+		UpdateFunc: func(_, obj interface{}) { s.onNodeChange(obj) },
+		DeleteFunc: func(obj interface{}) { s.onNodeChange(obj) },
+	}); err != nil {
+		return nil, errors.Wrap(err, "unable to watch Nodes")
+	}
+
+	go s.runPeriodicResync(ctx)
+
+	return s, nil
+}
+
+// runPeriodicResync calls reparseFull every resyncPeriod, until ctx is done. A dns or cloud address-resolver entry
+// is only re-resolved by reparseFull, which otherwise only runs on a ConfigMap change, so without this an instance
+// whose DNS name or cloud-reported address changes would never be noticed.
+func (s *InstanceStore) runPeriodicResync(ctx context.Context) {
+	ticker := time.NewTicker(resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reparseFull()
+		}
+	}
+}
+
+// Events returns the channel on which instance add/update/delete events are delivered. The caller must keep
+// draining it promptly: a reparseFull can emit more events than the channel's buffer in one pass (e.g. the initial
+// sync of a ConfigMap describing many instances), and delivery blocks until they are read. A consumer that falls
+// behind only delays its own events; it does not block other callers, since events are sent after the store's lock
+// is released. Pending sends are abandoned once the context passed to NewInstanceStore is done, so a stalled
+// consumer cannot wedge the store past shutdown.
+func (s *InstanceStore) Events() <-chan Event {
+	return s.events
+}
+
+// Instances returns a snapshot of every instance currently known to the store.
+func (s *InstanceStore) Instances() []*instance.Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	instances := make([]*instance.Info, 0, len(s.instances))
+	for _, i := range s.instances {
+		instances = append(instances, i)
+	}
+	return instances
+}
+
+// onNodeChange is invoked whenever a Node is added, updated, or deleted. An instance's associated Node, and the
+// annotations used as a secondary address source, can change independently of the windows-instances ConfigMap, so
+// every change to a Windows Node triggers a re-association of the already-resolved instances against the latest
+// Node list. This does not re-run address resolution, which may involve a DNS or cloud provider lookup per entry;
+// only a ConfigMap change, or the periodic resync, does that, via reparseFull.
+func (s *InstanceStore) onNodeChange(obj interface{}) {
+	node, ok := obj.(*core.Node)
+	if !ok {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			node, _ = tombstone.Obj.(*core.Node)
+		}
+	}
+	if node == nil || node.Labels[core.LabelOSStable] != "windows" {
+		return
+	}
+	s.reassociateNodes()
+}
+
+// onConfigMapChange is invoked whenever the windows-instances ConfigMap is added, updated, or deleted.
+func (s *InstanceStore) onConfigMapChange(obj interface{}) {
+	configMap, ok := obj.(*core.ConfigMap)
+	if !ok {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			configMap, _ = tombstone.Obj.(*core.ConfigMap)
+		}
+	}
+	if configMap == nil || configMap.Namespace != s.namespace || configMap.Name != InstanceConfigMap {
+		return
+	}
+	s.reparseFull()
+}
+
+// reparseFull re-parses the current ConfigMap data against the current Node list, asynchronously, and diffs the
+// result against the store's existing instances, emitting an Event for every addition, update, and removal. Address
+// validation happens on this goroutine so that a slow DNS lookup delays only the next set of events, not the
+// informer's delivery of the ConfigMap change that triggered it. If a newer reparse starts before this one finishes,
+// its result is discarded so that it cannot overwrite the newer one's events with stale data. reassociateNodes does
+// not participate in this generation counter: it merges into whatever reparseFull produces instead of replacing it,
+// so a Node event can never cause an in-flight, and genuinely newer, ConfigMap reparse to be discarded as stale.
+func (s *InstanceStore) reparseFull() {
+	s.mu.Lock()
+	s.generation++
+	generation := s.generation
+	s.mu.Unlock()
+
+	go func() {
+		instances, err := GetInstances(s.client, s.namespace)
+		if err != nil {
+			return
+		}
+		s.applyInstances(generation, instances)
+	}()
+}
+
+// reassociateNodes re-associates every already known instance with its current Node, asynchronously, without
+// re-running address resolution. It merges the updated Node field directly into the store's current instances when
+// the Node list arrives, rather than diffing a snapshot taken when it started, so it can never overwrite a
+// ConfigMap reparse that completes concurrently with a stale, pre-change instance set. Since no entry's configured
+// address changes here, this never adds, removes, or emits an Event for an instance; it only ever updates Node.
+func (s *InstanceStore) reassociateNodes() {
+	go func() {
+		nodes := &core.NodeList{}
+		if err := s.client.List(context.TODO(), nodes, client.MatchingLabels{core.LabelOSStable: "windows"}); err != nil {
+			return
+		}
+		s.mergeNodeAssociations(nodes)
+	}()
+}
+
+// mergeNodeAssociations re-associates every instance currently in the store with its Node in nodes, in place. It is
+// separated out from reassociateNodes so the merge itself, which is what must not race with reparseFull, can be
+// exercised without the List call and goroutine around it.
+func (s *InstanceStore) mergeNodeAssociations(nodes *core.NodeList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for address, i := range s.instances {
+		reassociated := *i
+		reassociated.Node, _ = nodeutil.FindByAddress(i.Addresses, nodes)
+		s.instances[address] = &reassociated
+	}
+}
+
+// applyInstances diffs instances against the store's existing instances and records the result, then emits an
+// Event for every addition, update, and removal. The diff is computed, and the store's state updated, while
+// s.mu is held; the events themselves are sent only after releasing it, so that a consumer which is slow to drain
+// Events(), or a reparse producing more events than the channel's buffer, cannot block while the lock is held and
+// in turn stall Instances() or the next reparse. Each send also selects on s.done, so that once the store's context
+// is cancelled a consumer that stopped draining Events() cannot leak this goroutine forever.
+func (s *InstanceStore) applyInstances(generation uint64, instances []*instance.Info) {
+	s.mu.Lock()
+	if generation != s.generation {
+		s.mu.Unlock()
+		return
+	}
+	var toEmit []Event
+	seen := make(map[string]bool, len(instances))
+	for _, i := range instances {
+		seen[i.Address()] = true
+		existing, found := s.instances[i.Address()]
+		s.instances[i.Address()] = i
+		if !found {
+			toEmit = append(toEmit, Event{Type: Added, Instance: i})
+		} else if !instancesEqual(existing, i) {
+			toEmit = append(toEmit, Event{Type: Updated, Instance: i})
+		}
+	}
+	for address, i := range s.instances {
+		if !seen[address] {
+			delete(s.instances, address)
+			toEmit = append(toEmit, Event{Type: Deleted, Instance: i})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, event := range toEmit {
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// instancesEqual reports whether two instance.Info describe the same instance the same way.
+func instancesEqual(a, b *instance.Info) bool {
+	if a.Username != b.Username || a.DNSName != b.DNSName || len(a.Addresses) != len(b.Addresses) {
+		return false
+	}
+	for i := range a.Addresses {
+		if a.Addresses[i] != b.Addresses[i] {
+			return false
+		}
+	}
+	if a.SSHPort != b.SSHPort || a.AuthMethod != b.AuthMethod || a.SecretRef != b.SecretRef ||
+		a.Platform != b.Platform || a.Hostname != b.Hostname {
+		return false
+	}
+	if !labelsEqual(a.Labels, b.Labels) || !taintsEqual(a.Taints, b.Taints) {
+		return false
+	}
+	return true
+}
+
+// labelsEqual reports whether two label sets are equal.
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// taintsEqual reports whether two taint lists contain the same taints, in the same order.
+func taintsEqual(a, b []core.Taint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package wiparser
+
+import (
+	"testing"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/instance"
+)
+
+func TestInstancesEqual(t *testing.T) {
+	base := &instance.Info{Addresses: []string{"10.0.0.5"}, Username: "Administrator", SSHPort: 22}
+	same := &instance.Info{Addresses: []string{"10.0.0.5"}, Username: "Administrator", SSHPort: 22}
+	if !instancesEqual(base, same) {
+		t.Error("expected equal instances to compare equal")
+	}
+
+	differentUsername := &instance.Info{Addresses: []string{"10.0.0.5"}, Username: "core", SSHPort: 22}
+	if instancesEqual(base, differentUsername) {
+		t.Error("expected a different username to compare unequal")
+	}
+
+	differentAddress := &instance.Info{Addresses: []string{"10.0.0.6"}, Username: "Administrator", SSHPort: 22}
+	if instancesEqual(base, differentAddress) {
+		t.Error("expected a different address to compare unequal")
+	}
+}
+
+func TestLabelsEqual(t *testing.T) {
+	if !labelsEqual(nil, nil) {
+		t.Error("expected two nil label sets to compare equal")
+	}
+	if !labelsEqual(map[string]string{"a": "1"}, map[string]string{"a": "1"}) {
+		t.Error("expected identical label sets to compare equal")
+	}
+	if labelsEqual(map[string]string{"a": "1"}, map[string]string{"a": "2"}) {
+		t.Error("expected label sets with a differing value to compare unequal")
+	}
+	if labelsEqual(map[string]string{"a": "1"}, map[string]string{"a": "1", "b": "2"}) {
+		t.Error("expected label sets of different sizes to compare unequal")
+	}
+}
+
+func TestTaintsEqual(t *testing.T) {
+	a := []core.Taint{{Key: "k", Value: "v", Effect: core.TaintEffectNoSchedule}}
+	b := []core.Taint{{Key: "k", Value: "v", Effect: core.TaintEffectNoSchedule}}
+	if !taintsEqual(a, b) {
+		t.Error("expected identical taint lists to compare equal")
+	}
+	c := []core.Taint{{Key: "k", Value: "other", Effect: core.TaintEffectNoSchedule}}
+	if taintsEqual(a, c) {
+		t.Error("expected taint lists with a differing value to compare unequal")
+	}
+}
+
+func newTestStore() *InstanceStore {
+	return &InstanceStore{
+		instances: make(map[string]*instance.Info),
+		events:    make(chan Event, 10),
+	}
+}
+
+func drainEvents(t *testing.T, s *InstanceStore) map[string]Event {
+	t.Helper()
+	events := make(map[string]Event)
+	for {
+		select {
+		case e := <-s.events:
+			events[e.Instance.Address()] = e
+		default:
+			return events
+		}
+	}
+}
+
+func TestApplyInstancesAddUpdateDelete(t *testing.T) {
+	s := newTestStore()
+
+	s.generation = 1
+	s.applyInstances(1, []*instance.Info{
+		{Addresses: []string{"10.0.0.5"}, Username: "Administrator"},
+		{Addresses: []string{"10.0.0.6"}, Username: "core"},
+	})
+	events := drainEvents(t, s)
+	if len(events) != 2 || events["10.0.0.5"].Type != Added || events["10.0.0.6"].Type != Added {
+		t.Fatalf("expected two Added events, got %+v", events)
+	}
+
+	s.generation = 2
+	s.applyInstances(2, []*instance.Info{
+		{Addresses: []string{"10.0.0.5"}, Username: "Administrator2"},
+		{Addresses: []string{"10.0.0.7"}, Username: "new"},
+	})
+	events = drainEvents(t, s)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events (updated, deleted, added), got %+v", events)
+	}
+	if events["10.0.0.5"].Type != Updated {
+		t.Errorf("expected 10.0.0.5 to be Updated, got %v", events["10.0.0.5"].Type)
+	}
+	if events["10.0.0.6"].Type != Deleted {
+		t.Errorf("expected 10.0.0.6 to be Deleted, got %v", events["10.0.0.6"].Type)
+	}
+	if events["10.0.0.7"].Type != Added {
+		t.Errorf("expected 10.0.0.7 to be Added, got %v", events["10.0.0.7"].Type)
+	}
+}
+
+func TestApplyInstancesStaleGenerationDiscarded(t *testing.T) {
+	s := newTestStore()
+	s.generation = 5
+
+	s.applyInstances(4, []*instance.Info{{Addresses: []string{"10.0.0.5"}, Username: "Administrator"}})
+
+	if len(s.instances) != 0 {
+		t.Errorf("expected a stale generation's result to be discarded, got %+v", s.instances)
+	}
+	events := drainEvents(t, s)
+	if len(events) != 0 {
+		t.Errorf("expected no events for a discarded generation, got %+v", events)
+	}
+}
+
+// TestReassociateDoesNotSupersedeConcurrentReparse reproduces the scenario where a Node event fires, and its
+// mergeNodeAssociations completes, while a reparseFull started earlier is still in flight. Since
+// mergeNodeAssociations does not touch s.generation, the later-finishing reparseFull must still be able to commit
+// its result instead of being discarded as stale.
+func TestReassociateDoesNotSupersedeConcurrentReparse(t *testing.T) {
+	s := newTestStore()
+	s.generation = 1
+	s.applyInstances(1, []*instance.Info{{Addresses: []string{"10.0.0.5"}, Username: "Administrator"}})
+	drainEvents(t, s)
+
+	// A reparseFull for a new ConfigMap revision starts, taking generation 2, but its GetInstances call (DNS
+	// lookups, etc.) is still in flight.
+	s.generation = 2
+	reparseGeneration := s.generation
+
+	// A Node event fires and completes first.
+	node := core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status:     core.NodeStatus{Addresses: []core.NodeAddress{{Type: core.NodeInternalIP, Address: "10.0.0.5"}}},
+	}
+	s.mergeNodeAssociations(&core.NodeList{Items: []core.Node{node}})
+	if s.generation != reparseGeneration {
+		t.Fatalf("mergeNodeAssociations must not advance the generation reparseFull is using, got %d, want %d",
+			s.generation, reparseGeneration)
+	}
+
+	// The in-flight reparseFull now finishes and must not be discarded as stale.
+	s.applyInstances(reparseGeneration, []*instance.Info{{Addresses: []string{"10.0.0.6"}, Username: "core"}})
+	events := drainEvents(t, s)
+	if len(events) != 2 || events["10.0.0.6"].Type != Added || events["10.0.0.5"].Type != Deleted {
+		t.Fatalf("expected the newer reparseFull result to be committed, got events %+v", events)
+	}
+}
+
+func TestApplyInstancesAbandonsSendWhenDone(t *testing.T) {
+	s := newTestStore()
+	s.events = make(chan Event) // unbuffered, and nothing ever reads from it
+	done := make(chan struct{})
+	s.done = done
+	close(done)
+
+	done2 := make(chan struct{})
+	go func() {
+		s.generation = 1
+		s.applyInstances(1, []*instance.Info{{Addresses: []string{"10.0.0.5"}, Username: "Administrator"}})
+		close(done2)
+	}()
+
+	select {
+	case <-done2:
+	case <-time.After(time.Second):
+		t.Fatal("applyInstances blocked on a send despite the store's context already being done")
+	}
+}
+
+func TestApplyInstancesNoChangeEmitsNoEvent(t *testing.T) {
+	s := newTestStore()
+	s.generation = 1
+	s.applyInstances(1, []*instance.Info{{Addresses: []string{"10.0.0.5"}, Username: "Administrator"}})
+	drainEvents(t, s)
+
+	s.generation = 2
+	s.applyInstances(2, []*instance.Info{{Addresses: []string{"10.0.0.5"}, Username: "Administrator"}})
+	events := drainEvents(t, s)
+	if len(events) != 0 {
+		t.Errorf("expected no events when nothing changed, got %+v", events)
+	}
+}
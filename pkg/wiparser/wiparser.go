@@ -13,8 +13,13 @@ import (
 
 	"github.com/openshift/windows-machine-config-operator/pkg/instance"
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeutil"
+	"github.com/openshift/windows-machine-config-operator/pkg/resolver"
 )
 
+// addressResolverField is the name of the ConfigMap entry field that selects a non-static address resolver, in the
+// form address-resolver=<method>[:<param>].
+const addressResolverField = "address-resolver"
+
 // InstanceConfigMap is the name of the ConfigMap where VMs to be configured should be described.
 const InstanceConfigMap = "windows-instances"
 
@@ -48,34 +53,96 @@ func Parse(instancesData map[string]string, nodes *core.NodeList) ([]*instance.I
 	if nodes == nil {
 		return nil, errors.New("nodes cannot be nil")
 	}
-	instances := make([]*instance.Info, 0)
-	// Get information about the instances from each entry. The expected key/value format for each entry is:
-	// <address>: username=<username>
-	for address, data := range instancesData {
-		if err := validateAddress(address); err != nil {
-			return nil, errors.Wrapf(err, "invalid address %s", address)
+	structuredInstances, err := parseStructuredInstances(instancesData, nodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse structured instance list")
+	}
+	instances := structuredInstances
+	// Get information about the remaining instances from each legacy entry. The expected key/value format for
+	// each entry is:
+	// <address>[,<address>...]: username=<username>[,address-resolver=<method>[:<param>]]
+	// Multiple comma-separated addresses can be given for a dual-stack instance, for example
+	// "10.0.0.5,192.168.1.5: username=Administrator". By default the ConfigMap key is used as-is for the
+	// instance's address(es); address-resolver selects a different method of determining the address, for
+	// instances whose address is not known up front or may change over time.
+	//
+	// A ConfigMap data key must match [-._a-zA-Z0-9]+, so it can contain neither the "," separating multiple
+	// addresses nor the ":" characters an IPv6 address requires. Parse accepts such keys, but they cannot be stored
+	// in a windows-instances ConfigMap: instances with more than one address, or with a literal IPv6 address, must
+	// instead be described using the structured instances.yaml/instances.json schema (see schema.go), whose address
+	// field is a YAML/JSON string value and is not subject to the ConfigMap key character restriction.
+	for key, data := range instancesData {
+		if key == instancesYAMLKey || key == instancesJSONKey {
+			continue
+		}
+		username, method, param, err := parseEntryData(data)
+		if err != nil {
+			return instances, errors.Wrapf(err, "unable to parse data for %s", key)
 		}
-		username, err := extractUsername(data)
+
+		addresses, err := resolveAddresses(key, method, param, nodes)
 		if err != nil {
-			return instances, errors.Wrapf(err, "unable to get username for %s", address)
+			return nil, errors.Wrapf(err, "unable to resolve address for %s", key)
 		}
 
 		// Get the associated node if the described instance has one
-		node, _ := nodeutil.FindByAddress(address, nodes)
-		instances = append(instances, instance.NewInfo(address, username, "", false, node))
+		node, _ := nodeutil.FindByAddress(addresses, nodes)
+		instances = append(instances, instance.NewInfo(addresses, username, "", false, node))
 	}
 	return instances, nil
 }
 
-// validateAddress checks that the given address is either an ipv4 address, or resolves to any ip address
+// resolveAddresses returns the addresses associated with a ConfigMap entry. For the static method, the entry key is
+// split and validated as usual. For every other method, the configured resolver is used to determine the address,
+// and is given the entry's Node from the node list, if one is found, in case a Node annotation is required to
+// resolve it.
+func resolveAddresses(key string, method resolver.Method, param string, nodes *core.NodeList) ([]string, error) {
+	if method == resolver.Static {
+		addresses := splitAddresses(key)
+		for _, address := range addresses {
+			if err := validateAddress(address); err != nil {
+				return nil, errors.Wrapf(err, "invalid address %s", address)
+			}
+		}
+		return addresses, nil
+	}
+
+	r, err := resolver.New(method, key, param)
+	if err != nil {
+		return nil, err
+	}
+	// The key of a non-static entry is not necessarily an address itself, so the associated node cannot always be
+	// found by matching it against Node addresses. The annotation method in particular requires a Node to resolve
+	// against, so its key is expected to be the Node's name instead.
+	var node *core.Node
+	if method == resolver.Annotation {
+		node, _ = nodeutil.FindByName(key, nodes)
+	} else {
+		node, _ = nodeutil.FindByAddress(splitAddresses(key), nodes)
+	}
+	address, err := r.Resolve(node)
+	if err != nil {
+		return nil, err
+	}
+	return []string{address}, nil
+}
+
+// splitAddresses splits a ConfigMap key into the individual addresses it contains. A key describing a single-stack
+// instance contains one address, while a key describing a dual-stack instance contains a comma-separated pair.
+func splitAddresses(key string) []string {
+	rawAddresses := strings.Split(key, ",")
+	addresses := make([]string, 0, len(rawAddresses))
+	for _, address := range rawAddresses {
+		addresses = append(addresses, strings.TrimSpace(address))
+	}
+	return addresses
+}
+
+// validateAddress checks that the given address is an IPv4 address, an IPv6 address, or resolves to any IP address
 func validateAddress(address string) error {
-	// first check if address is an IP address
+	// first check if address is an IP address, either IPv4 or IPv6
 	if parsedAddr := net.ParseIP(address); parsedAddr != nil {
-		if parsedAddr.To4() != nil {
-			return nil
-		}
-		// if the address parses into an IP but is not ipv4 it must be ipv6
-		return errors.Errorf("ipv6 is not supported")
+		return nil
 	}
 	// Do a check that the DNS provided is valid
 	addressList, err := net.LookupHost(address)
@@ -93,20 +160,65 @@ func GetNodeUsername(instancesData map[string]string, node *core.Node) (string,
 	if node == nil {
 		return "", errors.New("cannot get username for nil node")
 	}
-	// Find entry in ConfigMap that is associated to node via address
-	for _, address := range node.Status.Addresses {
-		if value, found := instancesData[address.Address]; found {
-			return extractUsername(value)
+	if username, found, err := getStructuredNodeUsername(instancesData, node); err != nil {
+		return "", err
+	} else if found {
+		return username, nil
+	}
+	// Find entry in ConfigMap that is associated to the node via any of its addresses, of any address family. A
+	// static entry's key is itself an address and can be matched directly; a resolver-based entry's key may
+	// instead be a hostname, instance ID, or Node name, so it must be resolved to an address first.
+	singleNodeList := &core.NodeList{Items: []core.Node{*node}}
+	for key, value := range instancesData {
+		if key == instancesYAMLKey || key == instancesJSONKey {
+			continue
+		}
+		username, method, param, err := parseEntryData(value)
+		if err != nil {
+			continue
+		}
+		if method == resolver.Static {
+			if nodeutil.Matches(node, splitAddresses(key)) {
+				return username, nil
+			}
+			continue
+		}
+		addresses, err := resolveAddresses(key, method, param, singleNodeList)
+		if err != nil {
+			continue
+		}
+		if nodeutil.Matches(node, addresses) {
+			return username, nil
 		}
 	}
 	return "", errors.Errorf("unable to find instance associated with node %s", node.GetName())
 }
 
-// extractUsername returns the username string from data in the form username=<username>
-func extractUsername(value string) (string, error) {
-	splitData := strings.SplitN(value, "=", 2)
-	if len(splitData) == 0 || splitData[0] != "username" {
-		return "", errors.New("data has an incorrect format")
+// parseEntryData parses a ConfigMap entry value, which is a comma-separated list of key=value fields, for example
+// "username=Administrator,address-resolver=dns:my-host.example.com". The only required field is username; if
+// address-resolver is not given, method defaults to resolver.Static and param is empty.
+func parseEntryData(value string) (username string, method resolver.Method, param string, err error) {
+	method = resolver.Static
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		splitField := strings.SplitN(field, "=", 2)
+		if len(splitField) != 2 {
+			return "", "", "", errors.New("data has an incorrect format")
+		}
+		key, fieldValue := splitField[0], splitField[1]
+		switch key {
+		case "username":
+			username = fieldValue
+		case addressResolverField:
+			resolverMethod, resolverParam, _ := strings.Cut(fieldValue, ":")
+			method = resolver.Method(resolverMethod)
+			param = resolverParam
+		default:
+			return "", "", "", errors.Errorf("unknown instance field %q", key)
+		}
+	}
+	if username == "" {
+		return "", "", "", errors.New("data has an incorrect format")
 	}
-	return splitData[1], nil
+	return username, method, param, nil
 }
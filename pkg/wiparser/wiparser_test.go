@@ -0,0 +1,179 @@
+package wiparser
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/resolver"
+)
+
+func TestSplitAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want []string
+	}{
+		{name: "single address", key: "10.0.0.5", want: []string{"10.0.0.5"}},
+		{name: "dual-stack", key: "10.0.0.5,192.168.1.5", want: []string{"10.0.0.5", "192.168.1.5"}},
+		{name: "whitespace is trimmed", key: "10.0.0.5, 192.168.1.5", want: []string{"10.0.0.5", "192.168.1.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitAddresses(tt.key)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	if err := validateAddress("10.0.0.5"); err != nil {
+		t.Errorf("unexpected error for IPv4 address: %v", err)
+	}
+	if err := validateAddress("fd00::5"); err != nil {
+		t.Errorf("unexpected error for IPv6 address: %v", err)
+	}
+	if err := validateAddress("not.a.valid.hostname.invalid"); err == nil {
+		t.Error("expected an error for a hostname that does not resolve")
+	}
+}
+
+func TestParseEntryData(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantUser    string
+		wantMethod  resolver.Method
+		wantParam   string
+		expectError bool
+	}{
+		{name: "username only", value: "username=Administrator", wantUser: "Administrator", wantMethod: resolver.Static},
+		{
+			name: "with address-resolver", value: "username=Administrator,address-resolver=dns:my-host.example.com",
+			wantUser: "Administrator", wantMethod: resolver.DNS, wantParam: "my-host.example.com",
+		},
+		{name: "missing username", value: "address-resolver=dns:my-host.example.com", expectError: true},
+		{name: "unknown field", value: "username=Administrator,bogus=1", expectError: true},
+		{name: "malformed field", value: "username", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, method, param, err := parseEntryData(tt.value)
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if username != tt.wantUser || method != tt.wantMethod || param != tt.wantParam {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", username, method, param,
+					tt.wantUser, tt.wantMethod, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestParseStaticEntries(t *testing.T) {
+	nodes := &core.NodeList{}
+	instancesData := map[string]string{
+		"10.0.0.5":             "username=Administrator",
+		"10.0.0.6,192.168.1.6": "username=core",
+	}
+	instances, err := Parse(instancesData, nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("got %d instances, want 2", len(instances))
+	}
+	byAddress := make(map[string]string)
+	for _, i := range instances {
+		byAddress[i.Address()] = i.Username
+	}
+	if byAddress["10.0.0.5"] != "Administrator" {
+		t.Errorf("got username %q for 10.0.0.5, want Administrator", byAddress["10.0.0.5"])
+	}
+	if byAddress["10.0.0.6"] != "core" {
+		t.Errorf("got username %q for 10.0.0.6, want core", byAddress["10.0.0.6"])
+	}
+}
+
+func TestParseNilNodes(t *testing.T) {
+	if _, err := Parse(map[string]string{}, nil); err == nil {
+		t.Error("expected an error when nodes is nil")
+	}
+}
+
+func TestParseAssociatesNodeByAddress(t *testing.T) {
+	node := core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status:     core.NodeStatus{Addresses: []core.NodeAddress{{Type: core.NodeInternalIP, Address: "10.0.0.5"}}},
+	}
+	nodes := &core.NodeList{Items: []core.Node{node}}
+	instances, err := Parse(map[string]string{"10.0.0.5": "username=Administrator"}, nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if instances[0].Node == nil || instances[0].Node.GetName() != "node-a" {
+		t.Errorf("expected instance to be associated with node-a, got %+v", instances[0].Node)
+	}
+}
+
+func TestGetNodeUsernameStaticEntry(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status:     core.NodeStatus{Addresses: []core.NodeAddress{{Type: core.NodeInternalIP, Address: "10.0.0.5"}}},
+	}
+	instancesData := map[string]string{"10.0.0.5": "username=Administrator"}
+	username, err := GetNodeUsername(instancesData, node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username != "Administrator" {
+		t.Errorf("got %q, want Administrator", username)
+	}
+}
+
+func TestGetNodeUsernameAnnotationEntry(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-a",
+			Annotations: map[string]string{"my.domain/address": "10.0.0.5"},
+		},
+		Status: core.NodeStatus{Addresses: []core.NodeAddress{{Type: core.NodeInternalIP, Address: "10.0.0.5"}}},
+	}
+	instancesData := map[string]string{
+		"node-a": "username=Administrator,address-resolver=annotation:my.domain/address",
+	}
+	username, err := GetNodeUsername(instancesData, node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username != "Administrator" {
+		t.Errorf("got %q, want Administrator", username)
+	}
+}
+
+func TestGetNodeUsernameNotFound(t *testing.T) {
+	node := &core.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	if _, err := GetNodeUsername(map[string]string{"10.0.0.5": "username=Administrator"}, node); err == nil {
+		t.Error("expected an error when no entry is associated with the node")
+	}
+}
+
+func TestGetNodeUsernameNilNode(t *testing.T) {
+	if _, err := GetNodeUsername(map[string]string{}, nil); err == nil {
+		t.Error("expected an error for a nil node")
+	}
+}